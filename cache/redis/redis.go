@@ -0,0 +1,111 @@
+// Package redis implements mongodb.Cache on top of a Redis client, so hot
+// file documents and chunks can be served without round-tripping to Mongo.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Cache is a mongodb.Cache backed by Redis. Keys are namespaced under
+// prefix so multiple stores can share one Redis instance.
+type Cache struct {
+	rdb    *goredis.Client
+	prefix string
+}
+
+// New wraps an existing Redis client. prefix is prepended to every key
+// (e.g. "filestore:") and may be empty.
+func New(rdb *goredis.Client, prefix string) *Cache {
+	return &Cache{rdb: rdb, prefix: prefix}
+}
+
+func (c *Cache) fileKey(fileId string) string {
+	return c.prefix + "file:" + fileId
+}
+
+func (c *Cache) chunkKey(fileId string, n int64) string {
+	return fmt.Sprintf("%schunk:%s:%d", c.prefix, fileId, n)
+}
+
+// GetFileDoc decodes with bson, not encoding/json: the document holds
+// driver-native types (int64 length, int32 chunk_size, bson.M metadata,
+// bson.A chunks) that JSON would flatten to float64/map[string]interface{}
+// and break every unchecked type assertion in the read paths.
+func (c *Cache) GetFileDoc(fileId string) (bson.M, bool) {
+	val, err := c.rdb.Get(context.Background(), c.fileKey(fileId)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(val, &doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+func (c *Cache) PutFileDoc(fileId string, doc bson.M, ttl time.Duration) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return
+	}
+	c.rdb.Set(context.Background(), c.fileKey(fileId), data, ttl)
+}
+
+// cachedChunk is the cached unit for one chunk: its still-encoded bytes plus
+// the codec chain it was encoded with. Unlike a file document, every field
+// here is a concrete type (no interface{}), so plain JSON round-trips it
+// exactly and doesn't need the bson treatment GetFileDoc/PutFileDoc require.
+type cachedChunk struct {
+	Data   []byte
+	Codecs []string
+}
+
+func (c *Cache) GetChunk(fileId string, n int64) (data []byte, codecs []string, ok bool) {
+	val, err := c.rdb.Get(context.Background(), c.chunkKey(fileId, n)).Bytes()
+	if err != nil {
+		return nil, nil, false
+	}
+	var chunk cachedChunk
+	if err := json.Unmarshal(val, &chunk); err != nil {
+		return nil, nil, false
+	}
+	return chunk.Data, chunk.Codecs, true
+}
+
+func (c *Cache) PutChunk(fileId string, n int64, data []byte, codecs []string, ttl time.Duration) {
+	val, err := json.Marshal(cachedChunk{Data: data, Codecs: codecs})
+	if err != nil {
+		return
+	}
+	c.rdb.Set(context.Background(), c.chunkKey(fileId, n), val, ttl)
+}
+
+// Invalidate removes the file document and every cached chunk for fileId.
+func (c *Cache) Invalidate(fileId string) error {
+	ctx := context.Background()
+
+	if err := c.rdb.Del(ctx, c.fileKey(fileId)).Err(); err != nil {
+		return err
+	}
+
+	pattern := fmt.Sprintf("%schunk:%s:*", c.prefix, fileId)
+	var keys []string
+	iter := c.rdb.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return c.rdb.Del(ctx, keys...).Err()
+}
@@ -1,8 +1,66 @@
 package filestore
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/4vn/filestore/localfs"
+	"github.com/4vn/filestore/mongodb"
+	"github.com/4vn/filestore/s3"
+	"github.com/4vn/filestore/seaweedfs"
+)
+
 type IFileStore interface {
 	Put(data []byte, metadata map[string]interface{}) (fileId string, err error)
 	Get(fileId string) (data []byte, metadata map[string]interface{}, err error)
+	PutStream(r io.Reader, metadata map[string]interface{}) (fileId string, err error)
+	GetStream(fileId string, w io.Writer) error
+	GetRange(fileId string, offset, length int64, w io.Writer) error
 	Delete(fileId string) error
 	Close() error
 }
+
+// Open constructs an IFileStore from a DSN, dispatching on its URL scheme:
+//
+//	mongodb://host/dbName         -> mongodb.FileStore
+//	s3://bucket/prefix?region=... -> the S3 driver, credentials from the
+//	                                 default AWS chain
+//	file:///path                  -> the local filesystem driver
+//	seaweed://host:port           -> the SeaweedFS filer driver
+func Open(dsn string) (IFileStore, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("filestore: invalid dsn %q: %w", dsn, err)
+	}
+
+	switch u.Scheme {
+	case "mongodb", "mongodb+srv":
+		dbName := strings.TrimPrefix(u.Path, "/")
+		return mongodb.NewFileStore(dsn, dbName, "")
+
+	case "s3":
+		region := u.Query().Get("region")
+		cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
+		if err != nil {
+			return nil, fmt.Errorf("filestore: loading aws config: %w", err)
+		}
+		client := awss3.NewFromConfig(cfg)
+		prefix := strings.TrimPrefix(u.Path, "/")
+		return s3.NewFileStore(client, u.Host, prefix), nil
+
+	case "file":
+		return localfs.NewFileStore(u.Path)
+
+	case "seaweed":
+		return seaweedfs.NewFileStore("http://" + u.Host), nil
+
+	default:
+		return nil, fmt.Errorf("filestore: unsupported dsn scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,132 @@
+// Package localfs implements filestore.IFileStore against the local
+// filesystem: each file is a plain file on disk plus a JSON sidecar for its
+// metadata, so it's usable without any external service.
+package localfs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileStore stores each file as dir/<fileId> with metadata alongside it as
+// dir/<fileId>.meta.json.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates dir if needed and returns a store rooted there.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (fs *FileStore) dataPath(fileId string) string {
+	return filepath.Join(fs.dir, fileId)
+}
+
+func (fs *FileStore) metaPath(fileId string) string {
+	return filepath.Join(fs.dir, fileId+".meta.json")
+}
+
+func (fs *FileStore) Put(data []byte, metadata map[string]interface{}) (fileId string, err error) {
+	return fs.PutStream(bytes.NewReader(data), metadata)
+}
+
+func (fs *FileStore) PutStream(r io.Reader, metadata map[string]interface{}) (fileId string, err error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	fileId = hex.EncodeToString(idBytes)
+
+	f, err := os.Create(fs.dataPath(fileId))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return "", err
+	}
+
+	metaBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(fs.metaPath(fileId), metaBytes, 0o644); err != nil {
+		return "", err
+	}
+
+	return fileId, nil
+}
+
+func (fs *FileStore) Get(fileId string) (data []byte, metadata map[string]interface{}, err error) {
+	data, err = os.ReadFile(fs.dataPath(fileId))
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata, err = fs.readMeta(fileId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, metadata, nil
+}
+
+func (fs *FileStore) GetStream(fileId string, w io.Writer) error {
+	f, err := os.Open(fs.dataPath(fileId))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (fs *FileStore) GetRange(fileId string, offset, length int64, w io.Writer) error {
+	f, err := os.Open(fs.dataPath(fileId))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, f, length)
+	return err
+}
+
+func (fs *FileStore) Delete(fileId string) error {
+	if err := os.Remove(fs.dataPath(fileId)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error deleting file: %v", err)
+	}
+	if err := os.Remove(fs.metaPath(fileId)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error deleting file: %v", err)
+	}
+	return nil
+}
+
+func (fs *FileStore) Close() error {
+	return nil
+}
+
+func (fs *FileStore) readMeta(fileId string) (map[string]interface{}, error) {
+	b, err := os.ReadFile(fs.metaPath(fileId))
+	if err != nil {
+		return nil, err
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,111 @@
+package mongodb
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cache is a read-through cache for file documents and individual chunks,
+// keyed by fileId so a FileStore can avoid round-trips to Mongo for hot
+// files. Implementations (e.g. filestore/cache/redis) decide their own key
+// layout and TTL mechanics.
+type Cache interface {
+	GetFileDoc(fileId string) (bson.M, bool)
+	PutFileDoc(fileId string, doc bson.M, ttl time.Duration)
+	// GetChunk/PutChunk cache a chunk's still-encoded bytes alongside the
+	// codec chain it was actually encoded with (see putChunk in dedup.go),
+	// since content-addressed chunks are shared across files and stores
+	// whose own codec configuration may differ from whichever one wrote it.
+	GetChunk(fileId string, n int64) (data []byte, codecs []string, ok bool)
+	PutChunk(fileId string, n int64, data []byte, codecs []string, ttl time.Duration)
+	Invalidate(fileId string) error
+}
+
+// CacheStats summarizes a FileStore's cache effectiveness since construction.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	Bytes  int64
+}
+
+// WithCache attaches a read-through Cache to the store. Cached entries are
+// kept for ttl before the store re-reads them from Mongo.
+func WithCache(cache Cache, ttl time.Duration) Option {
+	return func(fs *FileStore) {
+		fs.cache = cache
+		fs.cacheTTL = ttl
+	}
+}
+
+// Stats reports cache hit/miss counts and bytes served from cache, so
+// operators can validate cache effectiveness.
+func (fs *FileStore) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadInt64(&fs.cacheHits),
+		Misses: atomic.LoadInt64(&fs.cacheMisses),
+		Bytes:  atomic.LoadInt64(&fs.cacheBytes),
+	}
+}
+
+// cachedFileDoc fetches a file document from the cache, falling back to
+// Mongo and populating the cache on a miss. Safe to call with no cache
+// configured.
+func (fs *FileStore) cachedFileDoc(fileID primitive.ObjectID) (bson.M, error) {
+	fileId := fileID.Hex()
+
+	if fs.cache != nil {
+		if doc, ok := fs.cache.GetFileDoc(fileId); ok {
+			atomic.AddInt64(&fs.cacheHits, 1)
+			return doc, nil
+		}
+		atomic.AddInt64(&fs.cacheMisses, 1)
+	}
+
+	var doc bson.M
+	err := fs.filesColl.FindOne(context.Background(), bson.M{"_id": fileID}).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.cache != nil {
+		fs.cache.PutFileDoc(fileId, doc, fs.cacheTTL)
+	}
+
+	return doc, nil
+}
+
+// cachedChunk fetches one (still codec-encoded) chunk, identified by its
+// content hash, from the cache, falling back to Mongo and populating the
+// cache on a miss. n is the chunk's ordinal position within fileId, used
+// only for the cache key. The returned codecs are the chain the chunk was
+// actually encoded with (see putChunk), not necessarily this FileStore's own.
+func (fs *FileStore) cachedChunk(fileID primitive.ObjectID, n int64, hash string) (data []byte, codecs []string, err error) {
+	fileId := fileID.Hex()
+
+	if fs.cache != nil {
+		if data, codecs, ok := fs.cache.GetChunk(fileId, n); ok {
+			atomic.AddInt64(&fs.cacheHits, 1)
+			atomic.AddInt64(&fs.cacheBytes, int64(len(data)))
+			return data, codecs, nil
+		}
+		atomic.AddInt64(&fs.cacheMisses, 1)
+	}
+
+	var chunkDoc bson.M
+	err = fs.chunksColl.FindOne(context.Background(), bson.M{"_id": hash}).Decode(&chunkDoc)
+	if err != nil {
+		return nil, nil, err
+	}
+	data = chunkDoc["data"].(primitive.Binary).Data
+	codecs = chunkCodecList(chunkDoc)
+
+	if fs.cache != nil {
+		fs.cache.PutChunk(fileId, n, data, codecs, fs.cacheTTL)
+	}
+
+	return data, codecs, nil
+}
@@ -0,0 +1,208 @@
+package mongodb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ChunkCodec transforms chunk bytes before they are written to Mongo and
+// reverses the transform on read. A FileStore applies its codecs in
+// registration order on Put and in reverse order on Get, so the chain
+// composes like io.Writer wrapping (e.g. compress-then-encrypt).
+type ChunkCodec interface {
+	Encode(data []byte) ([]byte, error)
+	Decode(data []byte) ([]byte, error)
+	Name() string
+}
+
+// Option configures a FileStore at construction time.
+type Option func(*FileStore)
+
+// WithCompression appends a compression codec to the store's chunk
+// transform pipeline. kind must be one of "zstd", "gzip", or "snappy".
+func WithCompression(kind string) Option {
+	return func(fs *FileStore) {
+		switch kind {
+		case "zstd":
+			fs.codecs = append(fs.codecs, zstdCodec{})
+		case "gzip":
+			fs.codecs = append(fs.codecs, gzipCodec{})
+		case "snappy":
+			fs.codecs = append(fs.codecs, snappyCodec{})
+		}
+	}
+}
+
+// WithEncryption appends an AES-GCM encryption codec to the store's chunk
+// transform pipeline. aesGCMKey must be 16, 24, or 32 bytes (AES-128/192/256).
+// Each encoded chunk carries its own random nonce, so the key may be reused
+// safely across chunks and files.
+func WithEncryption(aesGCMKey []byte) Option {
+	return func(fs *FileStore) {
+		fs.codecs = append(fs.codecs, &aesGCMCodec{key: aesGCMKey})
+	}
+}
+
+// encodeChunk runs data through the store's codec chain in registration
+// order, used on write.
+func (fs *FileStore) encodeChunk(data []byte) ([]byte, error) {
+	var err error
+	for _, codec := range fs.codecs {
+		data, err = codec.Encode(data)
+		if err != nil {
+			return nil, fmt.Errorf("mongodb: %s encode: %w", codec.Name(), err)
+		}
+	}
+	return data, nil
+}
+
+// decodeChunkWithChain reverses the codec chain named in chunk's persisted
+// record (see codecNames) rather than this FileStore's own fs.codecs, so a
+// chunk decodes correctly even if the instance's configured codecs differ
+// from (or were added after) whoever originally wrote it. Each named codec
+// is looked up among fs.codecs by name, since that's where its runtime
+// config (e.g. the AES-GCM key) lives; a name with no match means this
+// store isn't configured with a codec the chunk actually needs.
+func (fs *FileStore) decodeChunkWithChain(data []byte, names []string) ([]byte, error) {
+	byName := fs.codecsByName()
+	var err error
+	for i := len(names) - 1; i >= 0; i-- {
+		codec, ok := byName[names[i]]
+		if !ok {
+			return nil, fmt.Errorf("mongodb: chunk was encoded with codec %q, which this store is not configured with", names[i])
+		}
+		data, err = codec.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("mongodb: %s decode: %w", names[i], err)
+		}
+	}
+	return data, nil
+}
+
+// codecsByName indexes the store's configured codecs for per-chunk chain
+// lookups (see decodeChunkWithChain).
+func (fs *FileStore) codecsByName() map[string]ChunkCodec {
+	byName := make(map[string]ChunkCodec, len(fs.codecs))
+	for _, codec := range fs.codecs {
+		byName[codec.Name()] = codec
+	}
+	return byName
+}
+
+// codecNames returns the codec chain's names in registration order, for
+// persisting alongside each chunk (see putChunk).
+func (fs *FileStore) codecNames() []string {
+	names := make([]string, len(fs.codecs))
+	for i, codec := range fs.codecs {
+		names[i] = codec.Name()
+	}
+	return names
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) Name() string { return "zstd" }
+
+func (zstdCodec) Encode(data []byte) ([]byte, error) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, nil), nil
+}
+
+func (zstdCodec) Decode(data []byte) ([]byte, error) {
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+	return dec.DecodeAll(data, nil)
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string { return "gzip" }
+
+func (gzipCodec) Encode(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+type snappyCodec struct{}
+
+func (snappyCodec) Name() string { return "snappy" }
+
+func (snappyCodec) Encode(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+func (snappyCodec) Decode(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
+// aesGCMCodec encrypts each chunk independently with AES-GCM, prefixing the
+// ciphertext with a fresh random nonce so the same key is safe to reuse
+// across every chunk of every file.
+type aesGCMCodec struct {
+	key []byte
+}
+
+func (c *aesGCMCodec) Name() string { return "aes-gcm" }
+
+func (c *aesGCMCodec) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (c *aesGCMCodec) Encode(data []byte) ([]byte, error) {
+	gcm, err := c.gcm()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+func (c *aesGCMCodec) Decode(data []byte) ([]byte, error) {
+	gcm, err := c.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted chunk shorter than nonce")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
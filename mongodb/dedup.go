@@ -0,0 +1,73 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// putChunk content-addresses data by its (plaintext) SHA-256, upserts it
+// into the chunks collection under that hash with a reference count, and
+// returns the hash. Identical chunks across files or within the same file
+// are stored once; codec-encoded bytes are only written on first insert,
+// since re-encoding would just reproduce (or, for a randomized codec like
+// AES-GCM, needlessly duplicate) the same plaintext.
+//
+// The codec chain used for this encoding is recorded on the chunk itself
+// (also only on first insert) rather than on the file document: because a
+// chunk is shared across every file and FileStore instance that happens to
+// write the same plaintext, whichever writer's bytes win the upsert also
+// owns the chain needed to decode them. Recording the chain on the file
+// document instead would make a second FileStore with different codec
+// options believe its own (unwritten) chain applies to bytes it never
+// actually encoded.
+func (fs *FileStore) putChunk(data []byte) (hash string, err error) {
+	sum := sha256.Sum256(data)
+	hash = hex.EncodeToString(sum[:])
+
+	encoded, err := fs.encodeChunk(data)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = fs.chunksColl.UpdateOne(
+		context.Background(),
+		bson.M{"_id": hash},
+		bson.M{
+			"$setOnInsert": bson.M{"data": encoded, "codecs": fs.codecNames()},
+			"$inc":         bson.M{"refcount": 1},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// chunkHashList reads a file document's ordered chunk hash array.
+func chunkHashList(fileDoc bson.M) []string {
+	raw, _ := fileDoc["chunks"].(bson.A)
+	return stringArray(raw)
+}
+
+// chunkCodecList reads a chunk document's persisted codec chain (see
+// putChunk), the chain actually used to encode the bytes stored under it.
+func chunkCodecList(chunkDoc bson.M) []string {
+	raw, _ := chunkDoc["codecs"].(bson.A)
+	return stringArray(raw)
+}
+
+// stringArray converts a bson.A of strings (the shape the driver decodes a
+// string array field into) to a []string.
+func stringArray(raw bson.A) []string {
+	out := make([]string, len(raw))
+	for i, v := range raw {
+		out[i], _ = v.(string)
+	}
+	return out
+}
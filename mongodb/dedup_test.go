@@ -0,0 +1,154 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newTestFileStore connects to MONGODB_URI for an integration test, or
+// skips if it isn't set — there's no in-memory Mongo fake in this repo, so
+// these tests need a real server the same way a deployed FileStore would.
+func newTestFileStore(t *testing.T) *FileStore {
+	t.Helper()
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI not set, skipping integration test")
+	}
+
+	collPrefix := fmt.Sprintf("dedup_test_%d_", time.Now().UnixNano())
+	fs, err := NewFileStore(uri, "filestore_test", collPrefix)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	t.Cleanup(func() {
+		fs.filesColl.Drop(context.Background())
+		fs.chunksColl.Drop(context.Background())
+		fs.Close()
+	})
+	return fs
+}
+
+func chunkRefcount(t *testing.T, fs *FileStore, hash string) (int32, bool) {
+	t.Helper()
+	var doc bson.M
+	err := fs.chunksColl.FindOne(context.Background(), bson.M{"_id": hash}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, false
+	}
+	if err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	return doc["refcount"].(int32), true
+}
+
+// TestReleaseChunkDeletesOnLastReference confirms the common path: a chunk
+// referenced by exactly one file is removed once that reference is released.
+func TestReleaseChunkDeletesOnLastReference(t *testing.T) {
+	fs := newTestFileStore(t)
+
+	hash, err := fs.putChunk([]byte("only one file references this chunk"))
+	if err != nil {
+		t.Fatalf("putChunk: %v", err)
+	}
+
+	if err := fs.releaseChunk(hash); err != nil {
+		t.Fatalf("releaseChunk: %v", err)
+	}
+
+	if _, ok := chunkRefcount(t, fs, hash); ok {
+		t.Fatalf("chunk %s still exists after its only reference was released", hash)
+	}
+}
+
+// TestReleaseChunkKeepsSharedChunk confirms a chunk referenced by two files
+// survives one of them being deleted.
+func TestReleaseChunkKeepsSharedChunk(t *testing.T) {
+	fs := newTestFileStore(t)
+
+	data := []byte("two files share this identical chunk")
+	hashA, err := fs.putChunk(data)
+	if err != nil {
+		t.Fatalf("putChunk (file A): %v", err)
+	}
+	hashB, err := fs.putChunk(data)
+	if err != nil {
+		t.Fatalf("putChunk (file B): %v", err)
+	}
+	if hashA != hashB {
+		t.Fatalf("identical content hashed differently: %s vs %s", hashA, hashB)
+	}
+
+	if err := fs.releaseChunk(hashA); err != nil {
+		t.Fatalf("releaseChunk: %v", err)
+	}
+
+	refcount, ok := chunkRefcount(t, fs, hashA)
+	if !ok {
+		t.Fatalf("chunk %s was deleted while file B still references it", hashA)
+	}
+	if refcount != 1 {
+		t.Fatalf("refcount = %d, want 1", refcount)
+	}
+}
+
+// TestReleaseChunkSurvivesConcurrentPut reproduces the race this request
+// fixes: a concurrent putChunk for the same content raises the refcount
+// back up between releaseChunk's decrement and its delete step, and the
+// delete must not fire anyway just because it observed refcount<=0 earlier.
+func TestReleaseChunkSurvivesConcurrentPut(t *testing.T) {
+	fs := newTestFileStore(t)
+
+	data := []byte("raced chunk content")
+	hash, err := fs.putChunk(data)
+	if err != nil {
+		t.Fatalf("putChunk: %v", err)
+	}
+
+	// First half of releaseChunk: decrement refcount to 0, as file A's
+	// Delete would, but don't run the delete step yet.
+	var chunkDoc bson.M
+	err = fs.chunksColl.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": hash},
+		bson.M{"$inc": bson.M{"refcount": -1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&chunkDoc)
+	if err != nil {
+		t.Fatalf("FindOneAndUpdate: %v", err)
+	}
+	if chunkDoc["refcount"].(int32) != 0 {
+		t.Fatalf("refcount = %v, want 0", chunkDoc["refcount"])
+	}
+
+	// Racing file B's Put lands here: the hash already exists, so the
+	// upsert's $setOnInsert is a no-op, but refcount still increments.
+	if _, err := fs.putChunk(data); err != nil {
+		t.Fatalf("racing putChunk: %v", err)
+	}
+	if refcount, ok := chunkRefcount(t, fs, hash); !ok || refcount != 1 {
+		t.Fatalf("refcount after racing put = %v (ok=%v), want 1", refcount, ok)
+	}
+
+	// Second half of releaseChunk: the conditional delete must see the
+	// refcount is no longer <=0 and leave the chunk (and file B's data)
+	// alone, rather than deleting unconditionally.
+	_, err = fs.chunksColl.DeleteOne(context.Background(), bson.M{"_id": hash, "refcount": bson.M{"$lte": 0}})
+	if err != nil {
+		t.Fatalf("DeleteOne: %v", err)
+	}
+
+	refcount, ok := chunkRefcount(t, fs, hash)
+	if !ok {
+		t.Fatalf("chunk %s was deleted despite the concurrent put raising its refcount back up", hash)
+	}
+	if refcount != 1 {
+		t.Fatalf("refcount = %d, want 1", refcount)
+	}
+}
@@ -0,0 +1,380 @@
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/klauspost/reedsolomon"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// ErasureFileStore stripes each chunk across dataShards+parityShards
+// independent MongoDB backends using Reed-Solomon erasure coding, so a file
+// survives the loss of up to parityShards backends without relying on
+// replica-set replication within a single deployment.
+type ErasureFileStore struct {
+	clients      []*mongo.Client
+	filesColls   []*mongo.Collection
+	chunksColls  []*mongo.Collection
+	dataShards   int
+	parityShards int
+}
+
+// NewErasureFileStore dials one *mongo.Client per URI and returns a store
+// that erasure-codes every chunk across all of them. len(uris) must equal
+// dataShards+parityShards.
+func NewErasureFileStore(uris []string, dbName, collPrefix string, dataShards, parityShards int) (*ErasureFileStore, error) {
+	if len(uris) != dataShards+parityShards {
+		return nil, fmt.Errorf("mongodb: erasure file store needs %d backends (%d data + %d parity), got %d", dataShards+parityShards, dataShards, parityShards, len(uris))
+	}
+
+	clients := make([]*mongo.Client, len(uris))
+	filesColls := make([]*mongo.Collection, len(uris))
+	chunksColls := make([]*mongo.Collection, len(uris))
+
+	for i, uri := range uris {
+		clientOptions := options.Client().ApplyURI(uri)
+		client, err := mongo.Connect(context.Background(), clientOptions)
+		if err != nil {
+			return nil, err
+		}
+		if err := client.Ping(context.Background(), readpref.Primary()); err != nil {
+			return nil, err
+		}
+
+		db := client.Database(dbName)
+		chunksColl := db.Collection(collPrefix + ChunksColl)
+
+		_, err = chunksColl.Indexes().CreateOne(
+			context.Background(),
+			mongo.IndexModel{
+				Keys: bson.D{
+					{Key: "files_id", Value: 1},
+					{Key: "n", Value: 1},
+				},
+				Options: &options.IndexOptions{Background: pBool(true), Unique: pBool(true)},
+			},
+			options.CreateIndexes(),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		clients[i] = client
+		filesColls[i] = db.Collection(collPrefix + FilesColl)
+		chunksColls[i] = chunksColl
+	}
+
+	return &ErasureFileStore{
+		clients:      clients,
+		filesColls:   filesColls,
+		chunksColls:  chunksColls,
+		dataShards:   dataShards,
+		parityShards: parityShards,
+	}, nil
+}
+
+func (fs *ErasureFileStore) Put(buffer []byte, metadata map[string]interface{}) (fileId string, err error) {
+	return fs.PutStream(bytes.NewReader(buffer), metadata)
+}
+
+func (fs *ErasureFileStore) PutStream(r io.Reader, metadata map[string]interface{}) (fileId string, err error) {
+	enc, err := reedsolomon.New(fs.dataShards, fs.parityShards)
+	if err != nil {
+		return "", err
+	}
+
+	fileID := primitive.NewObjectID()
+	uploadDate := time.Now()
+
+	md5Hash := md5.New()
+	chunkNumber := 0
+	var fileSize int64
+
+	buf := make([]byte, ChunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			chunkData := buf[:n]
+			md5Hash.Write(chunkData)
+			fileSize += int64(n)
+
+			shards, err := enc.Split(chunkData)
+			if err != nil {
+				return "", err
+			}
+			if err := enc.Encode(shards); err != nil {
+				return "", err
+			}
+
+			if err := fs.putShards(fileID, chunkNumber, n, shards); err != nil {
+				return "", err
+			}
+			chunkNumber++
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return "", readErr
+		}
+	}
+
+	fileDoc := bson.M{
+		"_id":         fileID,
+		"length":      fileSize,
+		"chunk_size":  ChunkSize,
+		"upload_date": uploadDate,
+		"md5":         fmt.Sprintf("%x", md5Hash.Sum(nil)),
+		"metadata":    metadata,
+		"erasure": bson.M{
+			"data_shards":   fs.dataShards,
+			"parity_shards": fs.parityShards,
+		},
+	}
+	for _, coll := range fs.filesColls {
+		if _, err := coll.InsertOne(context.Background(), fileDoc); err != nil {
+			return "", err
+		}
+	}
+
+	return fileID.Hex(), nil
+}
+
+// putShards writes one erasure-coded shard of a chunk to each backend.
+func (fs *ErasureFileStore) putShards(fileID primitive.ObjectID, n, chunkLen int, shards [][]byte) error {
+	for i, shard := range shards {
+		chunkDoc := bson.M{
+			"files_id":  fileID,
+			"n":         n,
+			"shard":     i,
+			"chunk_len": chunkLen,
+			"data":      shard,
+		}
+		if _, err := fs.chunksColls[i].InsertOne(context.Background(), chunkDoc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileDoc fetches the file document from the first backend, tried in order,
+// that returns it.
+func (fs *ErasureFileStore) fileDoc(fileID primitive.ObjectID) (bson.M, error) {
+	var doc bson.M
+	var err error
+	for _, coll := range fs.filesColls {
+		err = coll.FindOne(context.Background(), bson.M{"_id": fileID}).Decode(&doc)
+		if err == nil {
+			return doc, nil
+		}
+	}
+	return nil, err
+}
+
+// getChunk reads any dataShards of the dataShards+parityShards shards for
+// chunk n in parallel and reconstructs the original chunk bytes.
+func (fs *ErasureFileStore) getChunk(enc reedsolomon.Encoder, fileID primitive.ObjectID, n int64) ([]byte, error) {
+	total := fs.dataShards + fs.parityShards
+	shards := make([][]byte, total)
+	chunkLens := make([]int, total)
+
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(shardIdx int) {
+			defer wg.Done()
+			var chunkDoc bson.M
+			err := fs.chunksColls[shardIdx].FindOne(context.Background(), bson.M{"files_id": fileID, "n": n}).Decode(&chunkDoc)
+			if err != nil {
+				return
+			}
+			shards[shardIdx] = chunkDoc["data"].(primitive.Binary).Data
+			chunkLens[shardIdx] = int(chunkDoc["chunk_len"].(int32))
+		}(i)
+	}
+	wg.Wait()
+
+	present, chunkLen := 0, 0
+	for i, shard := range shards {
+		if shard != nil {
+			present++
+			chunkLen = chunkLens[i]
+		}
+	}
+	if present < fs.dataShards {
+		return nil, fmt.Errorf("mongodb: chunk %d has only %d of %d required shards", n, present, fs.dataShards)
+	}
+
+	if err := enc.Reconstruct(shards); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := enc.Join(&out, shards, chunkLen); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (fs *ErasureFileStore) Get(fileId string) (data []byte, metadata map[string]interface{}, err error) {
+	var buf bytes.Buffer
+	metadata, err = fs.getStream(fileId, &buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), metadata, nil
+}
+
+func (fs *ErasureFileStore) FastGet(fileId string) (data []byte, metadata map[string]interface{}, err error) {
+	return fs.Get(fileId)
+}
+
+func (fs *ErasureFileStore) getStream(fileId string, w io.Writer) (metadata map[string]interface{}, err error) {
+	fileID, err := primitive.ObjectIDFromHex(fileId)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := fs.fileDoc(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	fileSize := doc["length"].(int64)
+	chunkSize := int64(doc["chunk_size"].(int32))
+	metadata = doc["metadata"].(bson.M)
+	erasure := doc["erasure"].(bson.M)
+	dataShards := int(erasure["data_shards"].(int32))
+	parityShards := int(erasure["parity_shards"].(int32))
+
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, err
+	}
+
+	numChunks := (fileSize + chunkSize - 1) / chunkSize
+	for n := int64(0); n < numChunks; n++ {
+		chunkData, err := fs.getChunk(enc, fileID, n)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(chunkData); err != nil {
+			return nil, err
+		}
+	}
+
+	return metadata, nil
+}
+
+func (fs *ErasureFileStore) GetStream(fileId string, w io.Writer) error {
+	_, err := fs.getStream(fileId, w)
+	return err
+}
+
+// GetRange writes the [offset, offset+length) byte range of fileId to w,
+// reconstructing only the chunks that cover the requested range instead of
+// the whole file, mirroring FileStore.GetRange in stream.go.
+func (fs *ErasureFileStore) GetRange(fileId string, offset, length int64, w io.Writer) error {
+	fileID, err := primitive.ObjectIDFromHex(fileId)
+	if err != nil {
+		return err
+	}
+
+	doc, err := fs.fileDoc(fileID)
+	if err != nil {
+		return err
+	}
+
+	fileSize := doc["length"].(int64)
+	chunkSize := int64(doc["chunk_size"].(int32))
+	erasure := doc["erasure"].(bson.M)
+	dataShards := int(erasure["data_shards"].(int32))
+	parityShards := int(erasure["parity_shards"].(int32))
+
+	if offset < 0 || length < 0 || offset+length > fileSize {
+		return fmt.Errorf("mongodb: invalid range [%d, %d) for file of length %d", offset, offset+length, fileSize)
+	}
+	if length == 0 {
+		return nil
+	}
+
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return err
+	}
+
+	startChunk := offset / chunkSize
+	endChunk := (offset + length - 1) / chunkSize
+
+	remaining := length
+	for n := startChunk; n <= endChunk; n++ {
+		chunkData, err := fs.getChunk(enc, fileID, n)
+		if err != nil {
+			return err
+		}
+
+		chunkStart := n * chunkSize
+		lo := int64(0)
+		if offset > chunkStart {
+			lo = offset - chunkStart
+		}
+		hi := int64(len(chunkData))
+		if remaining < hi-lo {
+			hi = lo + remaining
+		}
+
+		written, err := w.Write(chunkData[lo:hi])
+		if err != nil {
+			return err
+		}
+		remaining -= int64(written)
+		if remaining == 0 {
+			break
+		}
+	}
+	if remaining != 0 {
+		return fmt.Errorf("mongodb: missing chunks for requested range of file %s", fileId)
+	}
+
+	return nil
+}
+
+func (fs *ErasureFileStore) Delete(fileId string) error {
+	fileID, err := primitive.ObjectIDFromHex(fileId)
+	if err != nil {
+		return err
+	}
+
+	for _, coll := range fs.filesColls {
+		if _, err := coll.DeleteOne(context.Background(), bson.M{"_id": fileID}); err != nil {
+			return fmt.Errorf("error deleting file: %v", err)
+		}
+	}
+	for _, coll := range fs.chunksColls {
+		if _, err := coll.DeleteMany(context.Background(), bson.M{"files_id": fileID}); err != nil {
+			return fmt.Errorf("error deleting file: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (fs *ErasureFileStore) Close() error {
+	for _, client := range fs.clients {
+		if err := client.Disconnect(context.Background()); err != nil {
+			return fmt.Errorf("error disconnecting from MongoDB: %v", err)
+		}
+	}
+	return nil
+}
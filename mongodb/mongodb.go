@@ -1,10 +1,9 @@
 package mongodb
 
 import (
+	"bytes"
 	"context"
-	"crypto/md5"
 	"fmt"
-	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -24,9 +23,17 @@ type FileStore struct {
 	client     *mongo.Client
 	filesColl  *mongo.Collection
 	chunksColl *mongo.Collection
+	codecs     []ChunkCodec
+
+	cache    Cache
+	cacheTTL time.Duration
+
+	cacheHits   int64
+	cacheMisses int64
+	cacheBytes  int64
 }
 
-func NewFileStore(uri, dbName, collPrefix string) (*FileStore, error) {
+func NewFileStore(uri, dbName, collPrefix string, opts ...Option) (*FileStore, error) {
 	clientOptions := options.Client().ApplyURI(uri)
 	client, err := mongo.Connect(context.Background(), clientOptions)
 	if err != nil {
@@ -45,115 +52,32 @@ func NewFileStore(uri, dbName, collPrefix string) (*FileStore, error) {
 	filesCollection := db.Collection(collPrefix + FilesColl)
 	chunksCollection := db.Collection(collPrefix + ChunksColl)
 
-	// create index
-	_, err = chunksCollection.Indexes().CreateOne(
-		context.Background(),
-		mongo.IndexModel{
-			Keys: bson.D{
-				{Key: "files_id", Value: 1},
-				{Key: "n", Value: 1},
-			},
-			Options: &options.IndexOptions{Background: pBool(true), Unique: pBool(true)},
-		},
-		options.CreateIndexes(),
-	)
-	if err != nil {
-		log.Fatal(err)
-	}
+	// Chunks are keyed by their content hash (see PutStream), so Mongo's
+	// default unique _id index is all the indexing they need.
 
-	return &FileStore{
+	fs := &FileStore{
 		client:     client,
 		filesColl:  filesCollection,
 		chunksColl: chunksCollection,
-	}, nil
-}
-
-func (fs *FileStore) Put(buffer []byte, metadata map[string]interface{}) (fileId string, err error) {
-	fileID := primitive.NewObjectID()
-	uploadDate := time.Now()
-
-	fileSize := int64(len(buffer))
-	chunkNumber := 0
-
-	md5Hash := md5.New()
-	md5Hash.Write(buffer)
-
-	for start := 0; start < len(buffer); start += ChunkSize {
-		end := start + ChunkSize
-		if end > len(buffer) {
-			end = len(buffer)
-		}
-
-		chunkData := buffer[start:end]
-
-		chunkDoc := bson.M{
-			"files_id": fileID,
-			"n":        chunkNumber,
-			"data":     chunkData,
-		}
-
-		_, err := fs.chunksColl.InsertOne(context.Background(), chunkDoc)
-		if err != nil {
-			return "", err
-		}
-
-		chunkNumber++
 	}
-
-	fileDoc := bson.M{
-		"_id":         fileID,
-		"length":      fileSize,
-		"chunk_size":  ChunkSize,
-		"upload_date": uploadDate,
-		"md5":         fmt.Sprintf("%x", md5Hash.Sum(nil)),
-		// "filename":    filename,
-		"metadata": metadata,
+	for _, opt := range opts {
+		opt(fs)
 	}
 
-	_, err = fs.filesColl.InsertOne(context.Background(), fileDoc)
-	if err != nil {
-		return "", err
-	}
+	return fs, nil
+}
 
-	// log.Printf("String data uploaded successfully as file %s with id %s\n", filename, fileID.Hex())
-	return fileID.Hex(), nil
+func (fs *FileStore) Put(buffer []byte, metadata map[string]interface{}) (fileId string, err error) {
+	return fs.PutStream(bytes.NewReader(buffer), metadata)
 }
 
 func (fs *FileStore) Get(fileId string) (data []byte, metadata map[string]interface{}, err error) {
-	fileID, err := primitive.ObjectIDFromHex(fileId)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	var fileDoc bson.M
-	err = fs.filesColl.FindOne(context.Background(), bson.M{"_id": fileID}).Decode(&fileDoc)
+	var buf bytes.Buffer
+	metadata, err = fs.getStream(fileId, &buf)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	//fileID := fileDoc["_id"]
-	fileSize := fileDoc["length"].(int64)
-	chunkSize := fileDoc["chunk_size"].(int32)
-	metadata = fileDoc["metadata"].(bson.M)
-
-	buffer := make([]byte, fileSize)
-	var offset int64
-
-	for offset < fileSize {
-		var chunkDoc bson.M
-		err = fs.chunksColl.FindOne(context.Background(), bson.M{"files_id": fileID, "n": offset / int64(chunkSize)}).Decode(&chunkDoc)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		chunkData := chunkDoc["data"].(primitive.Binary).Data
-		copy(buffer[offset:], chunkData)
-
-		offset += int64(len(chunkData))
-	}
-
-	// log.Printf("File %s downloaded successfully into buffer\n", fileId)
-	return buffer, metadata, nil
+	return buf.Bytes(), metadata, nil
 }
 
 func (fs *FileStore) FastGet(fileId string) (data []byte, metadata map[string]interface{}, err error) {
@@ -162,8 +86,7 @@ func (fs *FileStore) FastGet(fileId string) (data []byte, metadata map[string]in
 		return nil, nil, err
 	}
 
-	var fileDoc bson.M
-	err = fs.filesColl.FindOne(context.Background(), bson.M{"_id": fileID}).Decode(&fileDoc)
+	fileDoc, err := fs.cachedFileDoc(fileID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -171,9 +94,10 @@ func (fs *FileStore) FastGet(fileId string) (data []byte, metadata map[string]in
 	fileSize := fileDoc["length"].(int64)
 	chunkSize := fileDoc["chunk_size"].(int32)
 	metadata = fileDoc["metadata"].(bson.M)
+	chunkHashes := chunkHashList(fileDoc)
 
 	buffer := make([]byte, fileSize)
-	numChunks := (fileSize + int64(chunkSize) - 1) / int64(chunkSize)
+	numChunks := int64(len(chunkHashes))
 
 	// Channel to collect chunk data
 	type chunkResult struct {
@@ -186,13 +110,16 @@ func (fs *FileStore) FastGet(fileId string) (data []byte, metadata map[string]in
 	// Fetch chunks concurrently
 	for i := int64(0); i < numChunks; i++ {
 		go func(chunkIndex int64) {
-			var chunkDoc bson.M
-			err := fs.chunksColl.FindOne(context.Background(), bson.M{"files_id": fileID, "n": chunkIndex}).Decode(&chunkDoc)
+			raw, codecNames, err := fs.cachedChunk(fileID, chunkIndex, chunkHashes[chunkIndex])
+			if err != nil {
+				chunkChan <- chunkResult{index: chunkIndex, data: nil, err: err}
+				return
+			}
+			chunkData, err := fs.decodeChunkWithChain(raw, codecNames)
 			if err != nil {
 				chunkChan <- chunkResult{index: chunkIndex, data: nil, err: err}
 				return
 			}
-			chunkData := chunkDoc["data"].(primitive.Binary).Data
 			chunkChan <- chunkResult{index: chunkIndex, data: chunkData, err: nil}
 		}(i)
 	}
@@ -217,16 +144,59 @@ func (fs *FileStore) Delete(fileId string) error {
 		return err
 	}
 
-	_, err = fs.filesColl.DeleteOne(context.Background(), bson.M{"_id": fileID})
+	var fileDoc bson.M
+	err = fs.filesColl.FindOne(context.Background(), bson.M{"_id": fileID}).Decode(&fileDoc)
 	if err != nil {
 		return fmt.Errorf("error deleting file: %v", err)
 	}
 
-	_, err = fs.chunksColl.DeleteMany(context.Background(), bson.M{"files_id": fileID})
+	_, err = fs.filesColl.DeleteOne(context.Background(), bson.M{"_id": fileID})
 	if err != nil {
 		return fmt.Errorf("error deleting file: %v", err)
 	}
 
+	for _, hash := range chunkHashList(fileDoc) {
+		if err := fs.releaseChunk(hash); err != nil {
+			return fmt.Errorf("error deleting file: %v", err)
+		}
+	}
+
+	if fs.cache != nil {
+		if err := fs.cache.Invalidate(fileId); err != nil {
+			return fmt.Errorf("error invalidating cache: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// releaseChunk decrements a content-addressed chunk's refcount and removes
+// it once no file references it any more.
+func (fs *FileStore) releaseChunk(hash string) error {
+	var chunkDoc bson.M
+	err := fs.chunksColl.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": hash},
+		bson.M{"$inc": bson.M{"refcount": -1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&chunkDoc)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if chunkDoc["refcount"].(int32) <= 0 {
+		// Condition the delete on the refcount still being <=0: between our
+		// FindOneAndUpdate and this DeleteOne, a concurrent putChunk for the
+		// same content hash could have upserted (a no-op, the doc already
+		// exists) and incremented refcount back up to 1, in which case this
+		// delete must not fire or it destroys data a live file references.
+		// No match just means that race happened and the increment saved it.
+		_, err := fs.chunksColl.DeleteOne(context.Background(), bson.M{"_id": hash, "refcount": bson.M{"$lte": 0}})
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,202 @@
+// Package s3 implements filestore.IFileStore against an S3-compatible
+// bucket: Put maps to a streaming (multi-part above a size threshold)
+// PutObject, Get to GetObject, and the caller's metadata map is stored as a
+// JSON sidecar object, the only place it round-trips without losing types.
+// A best-effort stringified copy is also set as S3 user-metadata, purely so
+// the object is still inspectable from tools that only show headers.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// multipartThreshold is the part size the uploader switches to multi-part
+// upload above; it mirrors mongodb.ChunkSize so both drivers stream
+// comparable amounts of data per request.
+const multipartThreshold = 8 * 1024 * 1024
+
+// metadataHeaderLimit is conservatively below S3's 2KB user-metadata cap,
+// leaving room for header-encoding overhead. Headers are a best-effort,
+// human-inspectable copy only; the JSON sidecar is what Get actually reads.
+const metadataHeaderLimit = 1800
+
+// FileStore stores each file as object prefix+fileId in bucket.
+type FileStore struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewFileStore wraps an existing S3 client. prefix is prepended to every
+// object key and may be empty.
+func NewFileStore(client *s3.Client, bucket, prefix string) *FileStore {
+	return &FileStore{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (fs *FileStore) key(fileId string) string {
+	return fs.prefix + fileId
+}
+
+func (fs *FileStore) sidecarKey(fileId string) string {
+	return fs.prefix + fileId + ".metadata.json"
+}
+
+func (fs *FileStore) Put(data []byte, metadata map[string]interface{}) (fileId string, err error) {
+	return fs.PutStream(bytes.NewReader(data), metadata)
+}
+
+func (fs *FileStore) PutStream(r io.Reader, metadata map[string]interface{}) (fileId string, err error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	fileId = hex.EncodeToString(idBytes)
+
+	userMeta, sidecar, err := splitMetadata(metadata)
+	if err != nil {
+		return "", err
+	}
+
+	uploader := manager.NewUploader(fs.client, func(u *manager.Uploader) {
+		u.PartSize = multipartThreshold
+	})
+	_, err = uploader.Upload(context.Background(), &s3.PutObjectInput{
+		Bucket:   aws.String(fs.bucket),
+		Key:      aws.String(fs.key(fileId)),
+		Body:     r,
+		Metadata: userMeta,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_, err = fs.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.sidecarKey(fileId)),
+		Body:   bytes.NewReader(sidecar),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fileId, nil
+}
+
+func (fs *FileStore) Get(fileId string) (data []byte, metadata map[string]interface{}, err error) {
+	var buf bytes.Buffer
+	metadata, err = fs.getStream(fileId, &buf, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), metadata, nil
+}
+
+func (fs *FileStore) GetStream(fileId string, w io.Writer) error {
+	_, err := fs.getStream(fileId, w, "")
+	return err
+}
+
+func (fs *FileStore) GetRange(fileId string, offset, length int64, w io.Writer) error {
+	rangeHeader := fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	_, err := fs.getStream(fileId, w, rangeHeader)
+	return err
+}
+
+func (fs *FileStore) getStream(fileId string, w io.Writer, rangeHeader string) (map[string]interface{}, error) {
+	input := &s3.GetObjectInput{Bucket: aws.String(fs.bucket), Key: aws.String(fs.key(fileId))}
+	if rangeHeader != "" {
+		input.Range = aws.String(rangeHeader)
+	}
+
+	out, err := fs.client.GetObject(context.Background(), input)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	if _, err := io.Copy(w, out.Body); err != nil {
+		return nil, err
+	}
+
+	return fs.loadMetadata(fileId)
+}
+
+func (fs *FileStore) Delete(fileId string) error {
+	_, err := fs.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.key(fileId)),
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting file: %v", err)
+	}
+
+	// Best-effort: failing to remove the sidecar just leaves an orphaned
+	// metadata object behind; the file itself is already gone.
+	_, _ = fs.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.sidecarKey(fileId)),
+	})
+
+	return nil
+}
+
+func (fs *FileStore) Close() error {
+	return nil
+}
+
+// splitMetadata marshals metadata as the JSON sidecar object (the only
+// form that round-trips its value types) and, best-effort, also stringifies
+// it into S3 user-metadata headers purely so the object stays inspectable
+// from tools that only show headers. Headers are dropped entirely above
+// metadataHeaderLimit rather than silently truncated.
+func splitMetadata(metadata map[string]interface{}) (userMeta map[string]string, sidecar []byte, err error) {
+	sidecar, err = json.Marshal(metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := make(map[string]string, len(metadata))
+	size := 0
+	for k, v := range metadata {
+		s := fmt.Sprintf("%v", v)
+		headers[k] = s
+		size += len(k) + len(s)
+	}
+	if size > metadataHeaderLimit {
+		return nil, sidecar, nil
+	}
+	return headers, sidecar, nil
+}
+
+// loadMetadata reads the JSON sidecar object written by PutStream, the
+// authoritative copy of a file's metadata.
+func (fs *FileStore) loadMetadata(fileId string) (map[string]interface{}, error) {
+	out, err := fs.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(fs.sidecarKey(fileId)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3: loading metadata sidecar: %w", err)
+	}
+	defer out.Body.Close()
+
+	b, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
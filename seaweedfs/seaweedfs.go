@@ -0,0 +1,202 @@
+// Package seaweedfs implements filestore.IFileStore against a SeaweedFS
+// filer's HTTP API (https://github.com/seaweedfs/seaweedfs), so files can
+// be stored without running MongoDB or S3. A file's metadata is stored as a
+// JSON sidecar object rather than request headers: the filer (like net/http)
+// canonicalizes header names, so a key like "fileName" would come back as
+// "Filename", and non-scalar values would flatten to their Go %v string.
+package seaweedfs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// FileStore stores each file at filerURL/<fileId> via the filer's plain
+// HTTP upload/download/delete API.
+type FileStore struct {
+	filerURL string
+	client   *http.Client
+}
+
+// NewFileStore targets the filer reachable at filerURL (e.g.
+// "http://localhost:8888").
+func NewFileStore(filerURL string) *FileStore {
+	return &FileStore{
+		filerURL: strings.TrimRight(filerURL, "/"),
+		client:   http.DefaultClient,
+	}
+}
+
+func (fs *FileStore) sidecarPath(fileId string) string {
+	return "/" + fileId + ".metadata.json"
+}
+
+func (fs *FileStore) Put(data []byte, metadata map[string]interface{}) (fileId string, err error) {
+	return fs.PutStream(bytes.NewReader(data), metadata)
+}
+
+func (fs *FileStore) PutStream(r io.Reader, metadata map[string]interface{}) (fileId string, err error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	fileId = hex.EncodeToString(idBytes)
+
+	if err := fs.upload("/"+fileId, fileId, r); err != nil {
+		return "", err
+	}
+
+	metaBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	if err := fs.upload(fs.sidecarPath(fileId), fileId+".metadata.json", bytes.NewReader(metaBytes)); err != nil {
+		return "", err
+	}
+
+	return fileId, nil
+}
+
+// upload POSTs r to filerURL+path as a multipart file upload, the filer's
+// plain HTTP ingest API.
+func (fs *FileStore) upload(path, filename string, r io.Reader) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fs.filerURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := fs.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("seaweedfs: filer upload returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (fs *FileStore) Get(fileId string) (data []byte, metadata map[string]interface{}, err error) {
+	var buf bytes.Buffer
+	metadata, err = fs.getStream(fileId, &buf, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), metadata, nil
+}
+
+func (fs *FileStore) GetStream(fileId string, w io.Writer) error {
+	_, err := fs.getStream(fileId, w, "")
+	return err
+}
+
+func (fs *FileStore) GetRange(fileId string, offset, length int64, w io.Writer) error {
+	rangeHeader := fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	_, err := fs.getStream(fileId, w, rangeHeader)
+	return err
+}
+
+func (fs *FileStore) getStream(fileId string, w io.Writer, rangeHeader string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, fs.filerURL+"/"+fileId, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+
+	resp, err := fs.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("seaweedfs: filer download returned %s", resp.Status)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return nil, err
+	}
+
+	return fs.loadMetadata(fileId)
+}
+
+// loadMetadata reads the JSON sidecar object written by PutStream, the
+// authoritative copy of a file's metadata.
+func (fs *FileStore) loadMetadata(fileId string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, fs.filerURL+fs.sidecarPath(fileId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := fs.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("seaweedfs: loading metadata sidecar: filer returned %s", resp.Status)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (fs *FileStore) Delete(fileId string) error {
+	req, err := http.NewRequest(http.MethodDelete, fs.filerURL+"/"+fileId, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := fs.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error deleting file: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("error deleting file: filer returned %s", resp.Status)
+	}
+
+	// Best-effort: failing to clean up the sidecar just leaves an orphaned
+	// object behind, not a correctness problem for the caller.
+	if sidecarReq, err := http.NewRequest(http.MethodDelete, fs.filerURL+fs.sidecarPath(fileId), nil); err == nil {
+		if resp, err := fs.client.Do(sidecarReq); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	return nil
+}
+
+func (fs *FileStore) Close() error {
+	return nil
+}
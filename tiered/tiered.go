@@ -0,0 +1,183 @@
+// Package tiered implements filestore.IFileStore as a composite of a fast
+// primary driver and a slower secondary one, so callers can migrate off an
+// existing backend (e.g. mongodb.FileStore) without changing call sites:
+// writes land on the primary synchronously and replicate to the secondary
+// in the background, while reads and deletes are served by the primary.
+//
+// Each driver assigns its own fileId on Put, so the secondary's copy of a
+// file generally lives under a different id than the primary's. FileStore
+// records that mapping in a MappingStore so Delete can still reach the
+// secondary copy; by default this is an in-memory map that does not survive
+// a restart, so callers relying on Delete reaching the secondary across
+// restarts (or on ever addressing a file by its real secondary id to
+// complete a migration) should supply a durable MappingStore via
+// WithMappingStore.
+package tiered
+
+import (
+	"io"
+	"sync"
+)
+
+// driver is the subset of filestore.IFileStore that tiered depends on,
+// declared locally so this package doesn't need to import the root
+// filestore package (and, through Open, every driver it dispatches to).
+type driver interface {
+	Put(data []byte, metadata map[string]interface{}) (fileId string, err error)
+	Get(fileId string) (data []byte, metadata map[string]interface{}, err error)
+	PutStream(r io.Reader, metadata map[string]interface{}) (fileId string, err error)
+	GetStream(fileId string, w io.Writer) error
+	GetRange(fileId string, offset, length int64, w io.Writer) error
+	Delete(fileId string) error
+	Close() error
+}
+
+// MappingStore records the primary fileId -> secondary fileId mapping a
+// FileStore needs to reach a file's secondary copy. The default, used when
+// no Option supplies one, is an in-memory map that is lost on restart; pass
+// WithMappingStore a durable implementation (e.g. backed by a small Mongo
+// collection or local file) to make Delete reliable across restarts and to
+// support addressing a file by its real secondary id during a migration.
+type MappingStore interface {
+	Get(fileId string) (secondaryID string, ok bool)
+	Set(fileId, secondaryID string)
+	Delete(fileId string)
+}
+
+// memMappingStore is the default MappingStore: fast, but gone on restart.
+type memMappingStore struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+func newMemMappingStore() *memMappingStore {
+	return &memMappingStore{m: make(map[string]string)}
+}
+
+func (s *memMappingStore) Get(fileId string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	secondaryID, ok := s.m[fileId]
+	return secondaryID, ok
+}
+
+func (s *memMappingStore) Set(fileId, secondaryID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[fileId] = secondaryID
+}
+
+func (s *memMappingStore) Delete(fileId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, fileId)
+}
+
+// Option configures a FileStore at construction time.
+type Option func(*FileStore)
+
+// WithMappingStore replaces the default in-memory MappingStore with one
+// that survives a restart, making Delete reliably reach the secondary and
+// letting a caller look up a file's real secondary id mid-migration.
+func WithMappingStore(mapping MappingStore) Option {
+	return func(fs *FileStore) { fs.mapping = mapping }
+}
+
+// FileStore serves all reads from primary and mirrors every write to
+// secondary asynchronously and best-effort.
+type FileStore struct {
+	primary   driver
+	secondary driver
+	mapping   MappingStore
+
+	replicating sync.WaitGroup
+}
+
+// NewFileStore pairs a fast primary with a slower secondary to replicate to.
+func NewFileStore(primary, secondary driver, opts ...Option) *FileStore {
+	fs := &FileStore{
+		primary:   primary,
+		secondary: secondary,
+		mapping:   newMemMappingStore(),
+	}
+	for _, opt := range opts {
+		opt(fs)
+	}
+	return fs
+}
+
+func (fs *FileStore) Put(data []byte, metadata map[string]interface{}) (fileId string, err error) {
+	fileId, err = fs.primary.Put(data, metadata)
+	if err != nil {
+		return "", err
+	}
+
+	fs.replicating.Add(1)
+	go func() {
+		defer fs.replicating.Done()
+		secondaryID, err := fs.secondary.Put(data, metadata)
+		if err != nil {
+			return
+		}
+		fs.mapping.Set(fileId, secondaryID)
+	}()
+
+	return fileId, nil
+}
+
+func (fs *FileStore) PutStream(r io.Reader, metadata map[string]interface{}) (fileId string, err error) {
+	// Buffer once so the same bytes can be replicated to secondary after
+	// primary has already consumed the reader.
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return fs.Put(data, metadata)
+}
+
+func (fs *FileStore) Get(fileId string) (data []byte, metadata map[string]interface{}, err error) {
+	return fs.primary.Get(fileId)
+}
+
+func (fs *FileStore) GetStream(fileId string, w io.Writer) error {
+	return fs.primary.GetStream(fileId, w)
+}
+
+func (fs *FileStore) GetRange(fileId string, offset, length int64, w io.Writer) error {
+	return fs.primary.GetRange(fileId, offset, length, w)
+}
+
+func (fs *FileStore) Delete(fileId string) error {
+	err := fs.primary.Delete(fileId)
+
+	secondaryID, ok := fs.mapping.Get(fileId)
+	fs.mapping.Delete(fileId)
+	if !ok {
+		// No recorded mapping (mapping store lost across a restart, or
+		// replication hadn't finished yet) — fileId almost certainly isn't
+		// the secondary's own id for this file, so guessing it would risk
+		// deleting an unrelated object there. Leave the secondary copy for
+		// a durable MappingStore (see WithMappingStore) to reach instead.
+		return err
+	}
+
+	fs.replicating.Add(1)
+	go func() {
+		defer fs.replicating.Done()
+		fs.secondary.Delete(secondaryID) // best-effort async replication
+	}()
+
+	return err
+}
+
+// Close waits for in-flight replication to finish before closing both
+// underlying drivers, so a replication goroutine never touches a driver
+// after it's been closed.
+func (fs *FileStore) Close() error {
+	fs.replicating.Wait()
+
+	if err := fs.primary.Close(); err != nil {
+		return err
+	}
+	return fs.secondary.Close()
+}